@@ -0,0 +1,134 @@
+// Package cloudmeta detects which cloud provider eksctl is currently running
+// on by probing each provider's instance metadata service, so that tools
+// originally written against EC2's IMDS can degrade gracefully (or produce
+// useful labels) when run from CI/build environments hosted elsewhere.
+package cloudmeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/weaveworks/eksctl/pkg/imds"
+)
+
+// Provider identifies a cloud provider whose metadata service was detected.
+type Provider string
+
+const (
+	EC2   Provider = "EC2"
+	GCE   Provider = "GCE"
+	Azure Provider = "Azure"
+)
+
+const probeTimeout = 2 * time.Second
+
+// gceEndpoint and azureEndpoint are vars rather than consts so tests can
+// point them at an httptest.Server.
+var (
+	gceEndpoint   = "http://metadata.google.internal/computeMetadata/v1/instance/id"
+	azureEndpoint = "http://169.254.169.254/metadata/instance?api-version=2021-02-01"
+)
+
+// Metadata describes the provider a Suggest() probe detected, along with the
+// instance ID it reported, if any.
+type Metadata struct {
+	Provider   Provider
+	InstanceID string
+}
+
+// probe is implemented once per supported provider.
+type probe struct {
+	provider Provider
+	detect   func() (string, error)
+}
+
+// Suggest probes EC2, GCE and Azure metadata services in parallel with short
+// timeouts, and returns as soon as the first one responds successfully. It
+// returns an error only once every probe has failed.
+func Suggest() (*Metadata, error) {
+	probes := []probe{
+		{provider: EC2, detect: detectEC2},
+		{provider: GCE, detect: detectGCE},
+		{provider: Azure, detect: detectAzure},
+	}
+
+	results := make(chan *Metadata, len(probes))
+	for _, p := range probes {
+		p := p
+		go func() {
+			instanceID, err := p.detect()
+			if err != nil {
+				results <- nil
+				return
+			}
+			results <- &Metadata{Provider: p.provider, InstanceID: instanceID}
+		}()
+	}
+
+	// Race the probes: return as soon as one succeeds, rather than waiting
+	// for the slowest one. The remaining goroutines run to completion in
+	// the background, with each of their requests bounded by probeTimeout.
+	for range probes {
+		if result := <-results; result != nil {
+			return result, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to detect cloud provider: no metadata service responded")
+}
+
+func detectEC2() (string, error) {
+	client := imds.New()
+	client.SetTimeout(probeTimeout)
+	return client.GetMetadata("instance-id")
+}
+
+func detectGCE() (string, error) {
+	body, err := fetchWithHeader(gceEndpoint, "Metadata-Flavor", "Google")
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func detectAzure() (string, error) {
+	body, err := fetchWithHeader(azureEndpoint, "Metadata", "true")
+	if err != nil {
+		return "", err
+	}
+
+	var doc struct {
+		Compute struct {
+			VMID string `json:"vmId"`
+		} `json:"compute"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", fmt.Errorf("failed to parse azure instance metadata: %w", err)
+	}
+	return doc.Compute.VMID, nil
+}
+
+func fetchWithHeader(url, header, value string) ([]byte, error) {
+	client := &http.Client{Timeout: probeTimeout}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(header, value)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	return io.ReadAll(resp.Body)
+}
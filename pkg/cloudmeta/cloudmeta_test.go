@@ -0,0 +1,127 @@
+package cloudmeta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/weaveworks/eksctl/pkg/testutils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const ec2EndpointEnvVar = "AWS_EC2_METADATA_SERVICE_ENDPOINT"
+
+// notFoundServer always responds 404, simulating a metadata service that
+// isn't present on this host.
+func notFoundServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+}
+
+func TestCloudmeta(t *testing.T) {
+	testutils.RegisterAndRun(t)
+}
+
+var _ = Describe("detectGCE", func() {
+	var server *httptest.Server
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("returns the instance ID when the Metadata-Flavor header is honoured", func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Metadata-Flavor")).To(Equal("Google"))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("1234567890"))
+		}))
+		gceEndpoint = server.URL
+
+		instanceID, err := detectGCE()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instanceID).To(Equal("1234567890"))
+	})
+})
+
+var _ = Describe("detectAzure", func() {
+	var server *httptest.Server
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("extracts the VM ID from the instance metadata document", func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Metadata")).To(Equal("true"))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"compute": {"vmId": "abc-123"}}`))
+		}))
+		azureEndpoint = server.URL
+
+		instanceID, err := detectAzure()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instanceID).To(Equal("abc-123"))
+	})
+})
+
+var _ = Describe("Suggest", func() {
+	var (
+		ec2Server, gceServer, azureServer  *httptest.Server
+		origEC2Endpoint                    string
+		origGCEEndpoint, origAzureEndpoint string
+	)
+
+	BeforeEach(func() {
+		origEC2Endpoint = os.Getenv(ec2EndpointEnvVar)
+		origGCEEndpoint = gceEndpoint
+		origAzureEndpoint = azureEndpoint
+	})
+
+	AfterEach(func() {
+		os.Setenv(ec2EndpointEnvVar, origEC2Endpoint)
+		gceEndpoint = origGCEEndpoint
+		azureEndpoint = origAzureEndpoint
+		for _, server := range []*httptest.Server{ec2Server, gceServer, azureServer} {
+			if server != nil {
+				server.Close()
+			}
+		}
+	})
+
+	It("returns the first provider to respond successfully", func() {
+		ec2Server = notFoundServer()
+		os.Setenv(ec2EndpointEnvVar, ec2Server.URL)
+
+		azureServer = notFoundServer()
+		azureEndpoint = azureServer.URL
+
+		gceServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("1234567890"))
+		}))
+		gceEndpoint = gceServer.URL
+
+		md, err := Suggest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(md.Provider).To(Equal(GCE))
+		Expect(md.InstanceID).To(Equal("1234567890"))
+	})
+
+	It("returns an error when every provider fails", func() {
+		ec2Server = notFoundServer()
+		os.Setenv(ec2EndpointEnvVar, ec2Server.URL)
+
+		gceServer = notFoundServer()
+		gceEndpoint = gceServer.URL
+
+		azureServer = notFoundServer()
+		azureEndpoint = azureServer.URL
+
+		_, err := Suggest()
+		Expect(err).To(HaveOccurred())
+	})
+})
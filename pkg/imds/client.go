@@ -0,0 +1,246 @@
+// Package imds provides a client for the EC2 Instance Metadata Service (IMDS),
+// with IMDSv2 token caching and automatic retry on expired tokens.
+package imds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by GetMetadata when the metadata service responds
+// 404 for the requested path, e.g. when polling for an event that is not
+// currently active such as a pending spot interruption.
+var ErrNotFound = errors.New("imds: metadata not found")
+
+const (
+	// defaultEndpoint is the well-known link-local address IMDS listens on.
+	defaultEndpoint = "http://169.254.169.254"
+
+	// endpointEnvVar mirrors the environment variable used by the AWS SDK to
+	// override the IMDS endpoint, e.g. in tests or non-EC2 environments.
+	endpointEnvVar = "AWS_EC2_METADATA_SERVICE_ENDPOINT"
+
+	// tokenTTL is the TTL we request for IMDSv2 tokens, and therefore how
+	// long we can safely cache a token for before it needs refreshing.
+	tokenTTL = 21600 * time.Second
+
+	tokenHeader    = "X-aws-ec2-metadata-token"
+	tokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+
+	tokenPath            = "/latest/api/token"
+	identityDocumentPath = "/latest/dynamic/instance-identity/document"
+	userDataPath         = "/latest/user-data"
+	iamInfoPath          = "/latest/meta-data/iam/info"
+	metaDataPath         = "/latest/meta-data/"
+
+	requestTimeout = 5 * time.Second
+)
+
+// IdentityDocument is the structured equivalent of the JSON document served
+// at /latest/dynamic/instance-identity/document.
+type IdentityDocument struct {
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+	InstanceType     string `json:"instanceType"`
+	InstanceID       string `json:"instanceId"`
+	AccountID        string `json:"accountId"`
+	ImageID          string `json:"imageId"`
+	Architecture     string `json:"architecture"`
+}
+
+// IAMInfo is the structured equivalent of the JSON document served at
+// /latest/meta-data/iam/info.
+type IAMInfo struct {
+	Code               string `json:"Code"`
+	LastUpdated        string `json:"LastUpdated"`
+	InstanceProfileArn string `json:"InstanceProfileArn"`
+	InstanceProfileID  string `json:"InstanceProfileID"`
+}
+
+// IMDSClient talks to the EC2 Instance Metadata Service using IMDSv2,
+// caching its session token for the full TTL and transparently refreshing
+// it on expiry or on a 401 response from the metadata service.
+type IMDSClient struct {
+	endpoint   string
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// New returns an IMDSClient pointed at the default IMDS endpoint, or at the
+// address in AWS_EC2_METADATA_SERVICE_ENDPOINT if set, matching the
+// behaviour of the AWS SDK.
+func New() *IMDSClient {
+	endpoint := defaultEndpoint
+	if e := os.Getenv(endpointEnvVar); e != "" {
+		endpoint = e
+	}
+	return NewWithEndpoint(endpoint)
+}
+
+// NewWithEndpoint returns an IMDSClient pointed at the given endpoint,
+// primarily for use in tests against an httptest.Server.
+func NewWithEndpoint(endpoint string) *IMDSClient {
+	return &IMDSClient{
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// SetTimeout overrides the client's default per-request timeout, e.g. for
+// callers that need a shorter timeout while probing for IMDS's presence.
+func (c *IMDSClient) SetTimeout(timeout time.Duration) {
+	c.httpClient.Timeout = timeout
+}
+
+// GetInstanceIdentityDocument returns the instance's identity document.
+func (c *IMDSClient) GetInstanceIdentityDocument() (*IdentityDocument, error) {
+	body, err := c.get(identityDocumentPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance identity document: %w", err)
+	}
+
+	var doc IdentityDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse instance identity document: %w", err)
+	}
+	return &doc, nil
+}
+
+// GetMetadata returns the value at the given path under /latest/meta-data/,
+// e.g. GetMetadata("instance-id").
+func (c *IMDSClient) GetMetadata(path string) (string, error) {
+	body, err := c.get(metaDataPath + path)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to get metadata for %s: %w", path, err)
+	}
+	return string(body), nil
+}
+
+// GetUserData returns the instance's user data.
+func (c *IMDSClient) GetUserData() (string, error) {
+	body, err := c.get(userDataPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user data: %w", err)
+	}
+	return string(body), nil
+}
+
+// GetIAMInfo returns the instance's attached IAM instance profile info.
+func (c *IMDSClient) GetIAMInfo() (*IAMInfo, error) {
+	body, err := c.get(iamInfoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IAM info: %w", err)
+	}
+
+	var info IAMInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse IAM info: %w", err)
+	}
+	return &info, nil
+}
+
+// get performs a token-authenticated GET against path, transparently
+// retrying once with a freshly-fetched token if the server responds 401.
+func (c *IMDSClient) get(path string) ([]byte, error) {
+	token, err := c.getToken(false)
+	if err != nil {
+		return nil, err
+	}
+
+	body, status, err := c.doGet(path, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == http.StatusUnauthorized {
+		token, err = c.getToken(true)
+		if err != nil {
+			return nil, err
+		}
+		body, status, err = c.doGet(path, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if status == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", status, path)
+	}
+
+	return body, nil
+}
+
+func (c *IMDSClient) doGet(path, token string) ([]byte, int, error) {
+	req, err := http.NewRequest(http.MethodGet, c.endpoint+path, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set(tokenHeader, token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+// getToken returns a cached IMDSv2 token, fetching a new one if there is no
+// cached token, it has expired, or forceRefresh is set (as happens after a
+// 401 response from the metadata service).
+func (c *IMDSClient) getToken(forceRefresh bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !forceRefresh && c.token != "" && time.Now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	req, err := http.NewRequest(http.MethodPut, c.endpoint+tokenPath, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set(tokenTTLHeader, fmt.Sprintf("%d", int(tokenTTL.Seconds())))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get IMDS token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get IMDS token, status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read IMDS token: %w", err)
+	}
+
+	c.token = string(body)
+	c.tokenExpiry = time.Now().Add(tokenTTL)
+
+	return c.token, nil
+}
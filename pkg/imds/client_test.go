@@ -0,0 +1,156 @@
+package imds
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/weaveworks/eksctl/pkg/testutils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestIMDS(t *testing.T) {
+	testutils.RegisterAndRun(t)
+}
+
+var _ = Describe("IMDSClient", func() {
+	var (
+		server      *httptest.Server
+		client      *IMDSClient
+		tokenCalls  int
+		forceExpire bool
+	)
+
+	BeforeEach(func() {
+		tokenCalls = 0
+		forceExpire = false
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == tokenPath:
+				Expect(r.Method).To(Equal(http.MethodPut))
+				tokenCalls++
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("mock-token"))
+			case r.URL.Path == metaDataPath+"instance-id":
+				if forceExpire && r.Header.Get(tokenHeader) == "mock-token" && tokenCalls == 1 {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				Expect(r.Header.Get(tokenHeader)).To(Equal("mock-token"))
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("i-1234567890abcdef0"))
+			default:
+				Fail("unexpected request to " + r.URL.Path)
+			}
+		}))
+
+		client = NewWithEndpoint(server.URL)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("fetches metadata using a cached token", func() {
+		id, err := client.GetMetadata("instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal("i-1234567890abcdef0"))
+
+		_, err = client.GetMetadata("instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tokenCalls).To(Equal(1), "token should only be fetched once")
+	})
+
+	It("refreshes the token once on a 401 and retries", func() {
+		forceExpire = true
+
+		id, err := client.GetMetadata("instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal("i-1234567890abcdef0"))
+		Expect(tokenCalls).To(Equal(2), "token should be refreshed after a 401")
+	})
+})
+
+var _ = Describe("IMDSClient metadata calls", func() {
+	var (
+		server *httptest.Server
+		client *IMDSClient
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case tokenPath:
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("mock-token"))
+			case identityDocumentPath:
+				Expect(r.Header.Get(tokenHeader)).To(Equal("mock-token"))
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{
+					"instanceId": "i-1234567890abcdef0",
+					"region": "us-west-2",
+					"availabilityZone": "us-west-2a",
+					"instanceType": "m5.large",
+					"accountId": "123456789012",
+					"imageId": "ami-0abcdef1234567890",
+					"architecture": "x86_64"
+				}`))
+			case userDataPath:
+				Expect(r.Header.Get(tokenHeader)).To(Equal("mock-token"))
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("#!/bin/bash\necho hello"))
+			case iamInfoPath:
+				Expect(r.Header.Get(tokenHeader)).To(Equal("mock-token"))
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{
+					"Code": "Success",
+					"LastUpdated": "2026-01-01T00:00:00Z",
+					"InstanceProfileArn": "arn:aws:iam::123456789012:instance-profile/my-profile",
+					"InstanceProfileID": "AIPAEXAMPLE"
+				}`))
+			default:
+				Fail("unexpected request to " + r.URL.Path)
+			}
+		}))
+
+		client = NewWithEndpoint(server.URL)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("returns the instance identity document", func() {
+		doc, err := client.GetInstanceIdentityDocument()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(doc).To(Equal(&IdentityDocument{
+			InstanceID:       "i-1234567890abcdef0",
+			Region:           "us-west-2",
+			AvailabilityZone: "us-west-2a",
+			InstanceType:     "m5.large",
+			AccountID:        "123456789012",
+			ImageID:          "ami-0abcdef1234567890",
+			Architecture:     "x86_64",
+		}))
+	})
+
+	It("returns the user data", func() {
+		userData, err := client.GetUserData()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userData).To(Equal("#!/bin/bash\necho hello"))
+	})
+
+	It("returns the IAM info", func() {
+		info, err := client.GetIAMInfo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(&IAMInfo{
+			Code:               "Success",
+			LastUpdated:        "2026-01-01T00:00:00Z",
+			InstanceProfileArn: "arn:aws:iam::123456789012:instance-profile/my-profile",
+			InstanceProfileID:  "AIPAEXAMPLE",
+		}))
+	})
+})
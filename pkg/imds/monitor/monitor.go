@@ -0,0 +1,155 @@
+// Package monitor polls the EC2 Instance Metadata Service for spot
+// interruption and scheduled maintenance events.
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/weaveworks/eksctl/pkg/imds"
+)
+
+// EventType identifies the kind of event emitted by the Monitor.
+type EventType string
+
+const (
+	// SpotInterruption is emitted when IMDS reports a pending spot
+	// interruption for the instance.
+	SpotInterruption EventType = "SpotInterruption"
+	// ScheduledMaintenance is emitted when IMDS reports scheduled
+	// maintenance events for the instance.
+	ScheduledMaintenance EventType = "ScheduledMaintenance"
+)
+
+const (
+	spotInstanceActionPath   = "spot/instance-action"
+	scheduledMaintenancePath = "events/maintenance/scheduled"
+	defaultPollInterval      = 5 * time.Second
+	defaultFailureThreshold  = 5
+)
+
+// Event is emitted on the Monitor's Events channel on every poll in which
+// IMDS reports a spot interruption or scheduled maintenance notice; a notice
+// that stays in place is emitted again on each poll. Data holds the raw JSON
+// body returned by IMDS for the corresponding path.
+type Event struct {
+	Type EventType
+	Data string
+}
+
+// Config controls the Monitor's polling behaviour.
+type Config struct {
+	// PollInterval is how often to poll IMDS for events. Defaults to 5s.
+	PollInterval time.Duration
+	// FailureThreshold is the number of consecutive identical polling
+	// errors to tolerate before surfacing a fatal error on the Errors
+	// channel. Defaults to 5.
+	FailureThreshold int
+}
+
+// Monitor periodically polls IMDS for spot interruption and scheduled
+// maintenance notices, emitting typed events on Events. Identical
+// consecutive polling errors are suppressed up to FailureThreshold before
+// being surfaced on Errors, so that the monitor survives the occasional
+// transient IMDS error without becoming noisy.
+//
+// Events and Errors are the hook nodegroup commands (drain, delete) are
+// meant to react to, e.g. by draining a node early on SpotInterruption.
+// Wiring an actual caller onto them is left for whichever nodegroup command
+// needs it first; this package only ships the polling/event side.
+type Monitor struct {
+	client *imds.IMDSClient
+	config Config
+
+	Events chan Event
+	Errors chan error
+
+	stop chan struct{}
+}
+
+// New returns a Monitor that polls the given IMDS client. A zero Config
+// selects the defaults (5s poll interval, 5 consecutive failures before a
+// fatal error).
+func New(client *imds.IMDSClient, config Config) *Monitor {
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaultFailureThreshold
+	}
+
+	return &Monitor{
+		client: client,
+		config: config,
+		Events: make(chan Event),
+		Errors: make(chan error, 1),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start begins polling IMDS in a background goroutine. It returns
+// immediately; call Stop to terminate polling.
+func (m *Monitor) Start() {
+	go m.run()
+}
+
+// Stop terminates the polling goroutine started by Start.
+func (m *Monitor) Stop() {
+	close(m.stop)
+}
+
+func (m *Monitor) run() {
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+
+	// lastErr/consecutiveFailures are tracked per path, since the spot and
+	// scheduled-maintenance probes fail independently and their error
+	// strings (which embed the path) never match each other.
+	lastErr := map[string]string{}
+	consecutiveFailures := map[string]int{}
+
+	poll := func(path string, eventType EventType) {
+		data, err := m.client.GetMetadata(path)
+		if err != nil {
+			if errors.Is(err, imds.ErrNotFound) {
+				// No event currently active; this is the common case.
+				lastErr[path] = ""
+				consecutiveFailures[path] = 0
+				return
+			}
+
+			if err.Error() == lastErr[path] {
+				consecutiveFailures[path]++
+			} else {
+				lastErr[path] = err.Error()
+				consecutiveFailures[path] = 1
+			}
+
+			if consecutiveFailures[path] >= m.config.FailureThreshold {
+				select {
+				case m.Errors <- fmt.Errorf("polling %s failed %d times in a row: %w", path, consecutiveFailures[path], err):
+				default:
+				}
+			}
+			return
+		}
+
+		lastErr[path] = ""
+		consecutiveFailures[path] = 0
+		select {
+		case m.Events <- Event{Type: eventType, Data: data}:
+		case <-m.stop:
+		}
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			poll(spotInstanceActionPath, SpotInterruption)
+			poll(scheduledMaintenancePath, ScheduledMaintenance)
+		case <-m.stop:
+			return
+		}
+	}
+}
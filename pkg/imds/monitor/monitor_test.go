@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/eksctl/pkg/imds"
+	"github.com/weaveworks/eksctl/pkg/testutils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMonitor(t *testing.T) {
+	testutils.RegisterAndRun(t)
+}
+
+var _ = Describe("Monitor", func() {
+	var (
+		server *httptest.Server
+		mon    *Monitor
+	)
+
+	AfterEach(func() {
+		mon.Stop()
+		server.Close()
+	})
+
+	It("emits a SpotInterruption event once IMDS reports one", func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/latest/api/token":
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("mock-token"))
+			case "/latest/meta-data/spot/instance-action":
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"action": "terminate", "time": "2026-07-25T12:00:00Z"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		mon = New(imds.NewWithEndpoint(server.URL), Config{PollInterval: 10 * time.Millisecond})
+		mon.Start()
+
+		Eventually(mon.Events, time.Second).Should(Receive(WithTransform(func(e Event) EventType {
+			return e.Type
+		}, Equal(SpotInterruption))))
+	})
+
+	It("suppresses repeated identical errors until the failure threshold", func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/latest/api/token":
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("mock-token"))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+
+		mon = New(imds.NewWithEndpoint(server.URL), Config{PollInterval: 5 * time.Millisecond, FailureThreshold: 3})
+		mon.Start()
+
+		Eventually(mon.Errors, time.Second).Should(Receive())
+	})
+})
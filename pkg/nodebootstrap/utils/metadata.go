@@ -2,96 +2,80 @@ package utils
 
 import (
 	"fmt"
-	"io"
-	"net/http"
-	"time"
-)
-
-// GetEC2InstanceMetadata retrieves EC2 instance metadata and returns it as a map
-func GetEC2InstanceMetadata() (map[string]string, error) {
-	// Initialize the metadata map
-	metadata := make(map[string]string)
-
-	// Get IMDSv2 token
-	token, err := getIMDSToken()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get IMDS token: %w", err)
-	}
-
-	// Get instance ID
-	instanceID, err := getMetadata(token, "instance-id")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get instance ID: %w", err)
-	}
-	metadata["alpha.eksctl.io/instance-id"] = instanceID
 
-	// Get instance lifecycle
-	instanceLifecycle, err := getMetadata(token, "instance-life-cycle")
-	if err != nil {
-		// If instance lifecycle is not available, default to "on-demand"
-		instanceLifecycle = "on-demand"
-	}
-	metadata["node-lifecycle"] = instanceLifecycle
+	"github.com/weaveworks/eksctl/pkg/imds"
+)
 
-	return metadata, nil
+//go:generate mockgen -destination=../../utils/mocks/metadata.go -package=mocks -mock_names=Metadata=Metadata github.com/weaveworks/eksctl/pkg/nodebootstrap/utils Metadata
+
+// Metadata is the instance metadata surface nodebootstrap needs, abstracted
+// so callers (e.g. nodegroup labeling, drain flows) can substitute a mock in
+// tests instead of talking to a real IMDS endpoint.
+type Metadata interface {
+	// GetInstanceID returns the running instance's ID.
+	GetInstanceID() (string, error)
+	// GetInstanceLifecycle returns the running instance's lifecycle, e.g.
+	// "spot" or "on-demand".
+	GetInstanceLifecycle() (string, error)
+	// GetMetadata returns the value at the given path under
+	// /latest/meta-data/.
+	GetMetadata(path string) (string, error)
 }
 
-// getIMDSToken gets a token for IMDSv2
-func getIMDSToken() (string, error) {
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	req, err := http.NewRequest(http.MethodPut, "http://169.254.169.254/latest/api/token", nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "600")
+// ec2Metadata is the IMDS-backed implementation of Metadata.
+type ec2Metadata struct {
+	client *imds.IMDSClient
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+// NewMetadata returns an IMDS-backed Metadata. An empty baseURL talks to the
+// real metadata service (honouring AWS_EC2_METADATA_SERVICE_ENDPOINT);
+// tests can pass the URL of an httptest.Server instead.
+func NewMetadata(baseURL string) Metadata {
+	if baseURL == "" {
+		return &ec2Metadata{client: imds.New()}
 	}
-	defer resp.Body.Close()
+	return &ec2Metadata{client: imds.NewWithEndpoint(baseURL)}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get token, status code: %d", resp.StatusCode)
-	}
+func (m *ec2Metadata) GetInstanceID() (string, error) {
+	return m.client.GetMetadata("instance-id")
+}
 
-	token, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+func (m *ec2Metadata) GetInstanceLifecycle() (string, error) {
+	return m.client.GetMetadata("instance-life-cycle")
+}
 
-	return string(token), nil
+func (m *ec2Metadata) GetMetadata(path string) (string, error) {
+	return m.client.GetMetadata(path)
 }
 
-// getMetadata gets specific metadata using the provided token
-func getMetadata(token, path string) (string, error) {
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
+// GetEC2InstanceMetadata retrieves EC2 instance metadata and returns it as a map
+func GetEC2InstanceMetadata() (map[string]string, error) {
+	return getEC2InstanceMetadata(imds.New())
+}
 
-	req, err := http.NewRequest(http.MethodGet, "http://169.254.169.254/latest/meta-data/"+path, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("X-aws-ec2-metadata-token", token)
+func getEC2InstanceMetadata(client *imds.IMDSClient) (map[string]string, error) {
+	result := make(map[string]string)
 
-	resp, err := client.Do(req)
+	doc, err := client.GetInstanceIdentityDocument()
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to get instance identity document: %w", err)
 	}
-	defer resp.Body.Close()
+	result["alpha.eksctl.io/instance-id"] = doc.InstanceID
+	result["alpha.eksctl.io/region"] = doc.Region
+	result["alpha.eksctl.io/availability-zone"] = doc.AvailabilityZone
+	result["alpha.eksctl.io/instance-type"] = doc.InstanceType
+	result["alpha.eksctl.io/account-id"] = doc.AccountID
+	result["alpha.eksctl.io/image-id"] = doc.ImageID
+	result["alpha.eksctl.io/architecture"] = doc.Architecture
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get metadata for %s, status code: %d", path, resp.StatusCode)
-	}
-
-	data, err := io.ReadAll(resp.Body)
+	// Get instance lifecycle
+	instanceLifecycle, err := client.GetMetadata("instance-life-cycle")
 	if err != nil {
-		return "", err
+		// If instance lifecycle is not available, default to "on-demand"
+		instanceLifecycle = "on-demand"
 	}
+	result["node-lifecycle"] = instanceLifecycle
 
-	return string(data), nil
+	return result, nil
 }